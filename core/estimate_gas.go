@@ -0,0 +1,149 @@
+// Copyright 2021 The happyuc-go Authors
+// This file is part of the happyuc-go library.
+//
+// The happyuc-go library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The happyuc-go library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the happyuc-go library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/irchain/go-irchain/core/vm"
+)
+
+// revertSelector is the 4-byte selector of Solidity's standard
+// Error(string) revert encoding.
+var revertSelector = []byte{0x08, 0xc3, 0x79, 0xa0}
+
+var (
+	// ErrGasUintOverflow is returned by EstimateGas when no amount up to the
+	// supplied cap executes successfully.
+	ErrGasUintOverflow = errors.New("gas required exceeds allowance or always failing transaction")
+)
+
+// RevertError is returned by EstimateGas (and can be returned by callers of
+// ApplyMessage) when the EVM reverted. It carries the raw return data and,
+// when it decodes as a standard Error(string), the human-readable reason.
+type RevertError struct {
+	error
+	Reason     string // decoded reason, empty if the payload didn't decode
+	ReturnData []byte
+}
+
+func newRevertError(ret []byte) *RevertError {
+	reason, unpackErr := abiUnpackRevertReason(ret)
+	err := errors.New("execution reverted")
+	if unpackErr == nil {
+		err = fmt.Errorf("execution reverted: %s", reason)
+	}
+	return &RevertError{
+		error:      err,
+		Reason:     reason,
+		ReturnData: ret,
+	}
+}
+
+// abiUnpackRevertReason decodes the ABI-encoded string payload that follows
+// the Error(string) selector, returning an error if ret doesn't match that
+// shape (e.g. a bare revert() with no reason).
+func abiUnpackRevertReason(ret []byte) (string, error) {
+	if len(ret) < 4+32+32 || !bytes.Equal(ret[:4], revertSelector) {
+		return "", errors.New("malformed revert reason")
+	}
+	payload := ret[4:]
+	length := new(big.Int).SetBytes(payload[32:64]).Uint64()
+	if uint64(len(payload)) < 64+length {
+		return "", errors.New("malformed revert reason")
+	}
+	return string(payload[64 : 64+length]), nil
+}
+
+// estimateGasMessage overrides the Gas() of the wrapped Message so
+// EstimateGas can retry the same call at different gas limits without
+// mutating or re-signing the original message.
+type estimateGasMessage struct {
+	Message
+	gas uint64
+}
+
+func (m *estimateGasMessage) Gas() uint64 { return m.gas }
+
+// EstimateGas binary-searches [lo, hi] for the smallest gas limit at which
+// msg executes against evm without running out of gas or reverting. lo
+// should be a known-good lower bound (e.g. the intrinsic gas), hi the block
+// gas cap; gp additionally caps the search ceiling to the gas still
+// available in the block.
+func EstimateGas(evm *vm.EVM, msg Message, gp *GasPool, lo, hi uint64) (uint64, []byte, error) {
+	if avail := gp.Gas(); avail < hi {
+		hi = avail
+	}
+	cap := hi
+	// The loop only tries values strictly above lo; step below it so that lo
+	// itself is a candidate.
+	if lo > 0 {
+		lo--
+	}
+
+	// executable reports whether gas is enough to run msg to completion
+	// without reverting, the call's return data, and any hard error that
+	// should abort the search outright. Every trial runs under a snapshot so
+	// the binary search only simulates state changes instead of letting them
+	// accumulate across trials.
+	executable := func(gas uint64) (failed bool, ret []byte, err error) {
+		snap := evm.StateDB.Snapshot()
+		defer evm.StateDB.RevertToSnapshot(snap)
+
+		ret, _, failed, err = NewStateTransition(evm, &estimateGasMessage{msg, gas}, new(GasPool).AddGas(gas)).TransitionDb()
+		// An execution failure (out of gas, revert) comes back with failed
+		// set alongside the VM error; only errors without it abort the search.
+		if err != nil && !failed {
+			if errors.Is(err, ErrIntrinsicGas) {
+				return true, nil, nil // gas too low to even start; raise the lower bound
+			}
+			return true, nil, err
+		}
+		return failed, ret, nil
+	}
+
+	for lo+1 < hi {
+		mid := (lo + hi) / 2
+		failed, _, err := executable(mid)
+		if err != nil {
+			return 0, nil, err
+		}
+		if failed {
+			lo = mid
+		} else {
+			hi = mid
+		}
+	}
+	// The cap itself was never tried inside the loop; confirm it actually
+	// succeeds so callers don't get handed a gas limit that still fails.
+	if hi == cap {
+		failed, ret, err := executable(hi)
+		if err != nil {
+			return 0, nil, err
+		}
+		if failed {
+			if len(ret) > 0 {
+				return 0, nil, newRevertError(ret)
+			}
+			return 0, nil, ErrGasUintOverflow
+		}
+	}
+	return hi, nil, nil
+}
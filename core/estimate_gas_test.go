@@ -0,0 +1,141 @@
+// Copyright 2021 The happyuc-go Authors
+// This file is part of the happyuc-go library.
+//
+// The happyuc-go library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The happyuc-go library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the happyuc-go library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/irchain/go-irchain/common"
+)
+
+// packRevert builds the standard ABI-encoded Error(string) revert payload:
+// selector || offset(32) || length(32) || data, padded to a 32-byte multiple.
+func packRevert(reason string) []byte {
+	ret := append([]byte{}, revertSelector...)
+	ret = append(ret, common32(32)...)
+	ret = append(ret, common32(uint64(len(reason)))...)
+	ret = append(ret, reason...)
+	for len(ret)%32 != 4 {
+		ret = append(ret, 0)
+	}
+	return ret
+}
+
+func common32(v uint64) []byte {
+	b := make([]byte, 32)
+	new(big.Int).SetUint64(v).FillBytes(b)
+	return b
+}
+
+func TestAbiUnpackRevertReason(t *testing.T) {
+	ret := packRevert("out of gas for real")
+	reason, err := abiUnpackRevertReason(ret)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if reason != "out of gas for real" {
+		t.Fatalf("reason = %q, want %q", reason, "out of gas for real")
+	}
+}
+
+func TestAbiUnpackRevertReasonMalformed(t *testing.T) {
+	if _, err := abiUnpackRevertReason([]byte{0x01, 0x02}); err == nil {
+		t.Fatalf("expected error decoding a payload with no selector")
+	}
+	wrongSelector := append([]byte{0xde, 0xad, 0xbe, 0xef}, make([]byte, 64)...)
+	if _, err := abiUnpackRevertReason(wrongSelector); err == nil {
+		t.Fatalf("expected error decoding a payload with the wrong selector")
+	}
+}
+
+func TestNewRevertError(t *testing.T) {
+	ret := packRevert("nope")
+	revertErr := newRevertError(ret)
+	if revertErr.Reason != "nope" {
+		t.Fatalf("Reason = %q, want %q", revertErr.Reason, "nope")
+	}
+	if string(revertErr.ReturnData) != string(ret) {
+		t.Fatalf("ReturnData not preserved")
+	}
+	if revertErr.Error() != "execution reverted: nope" {
+		t.Fatalf("Error() = %q, want %q", revertErr.Error(), "execution reverted: nope")
+	}
+}
+
+func TestNewRevertErrorMalformedPayload(t *testing.T) {
+	revertErr := newRevertError([]byte{0x01, 0x02, 0x03, 0x04})
+	if revertErr.Reason != "" {
+		t.Fatalf("Reason = %q, want empty for a malformed payload", revertErr.Reason)
+	}
+	if revertErr.Error() != "execution reverted" {
+		t.Fatalf("Error() = %q, want %q", revertErr.Error(), "execution reverted")
+	}
+}
+
+// TestEstimateGasConvergesToIntrinsicGas drives EstimateGas end-to-end
+// against a plain value transfer to an EOA, which only ever needs intrinsic
+// gas to succeed, and checks the binary search lands on a gas limit that
+// actually executes.
+func TestEstimateGasConvergesToIntrinsicGas(t *testing.T) {
+	evm, _ := newTestTransitionEVM(t, nil)
+	to := common.HexToAddress("0x0a")
+	msg := &testMessage{
+		from:  common.HexToAddress("0xaa"),
+		to:    &to,
+		price: new(big.Int),
+		value: new(big.Int),
+	}
+	gp := new(GasPool).AddGas(1000000)
+
+	got, _, err := EstimateGas(evm, msg, gp, 0, 100000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, failed, err := NewStateTransition(evm, &estimateGasMessage{msg, got}, new(GasPool).AddGas(got)).TransitionDb(); err != nil || failed {
+		t.Fatalf("estimated gas %d doesn't actually execute: failed=%v err=%v", got, failed, err)
+	}
+	if _, _, failed, err := NewStateTransition(evm, &estimateGasMessage{msg, got - 1}, new(GasPool).AddGas(got-1)).TransitionDb(); err == nil && !failed {
+		t.Fatalf("gas %d (one below the estimate) unexpectedly succeeded", got-1)
+	}
+}
+
+// TestEstimateGasDoesNotMutateState checks that each binary-search trial
+// runs under a snapshot: the sender's nonce is bumped by every trial that
+// gets past intrinsic gas (see transitionDb), so if trials weren't reverted
+// the nonce would drift up by one for each such probe EstimateGas makes.
+func TestEstimateGasDoesNotMutateState(t *testing.T) {
+	evm, statedb := newTestTransitionEVM(t, nil)
+	sender := common.HexToAddress("0xaa")
+	to := common.HexToAddress("0x0a")
+	statedb.SetNonce(sender, 5)
+
+	msg := &testMessage{
+		from:  sender,
+		to:    &to,
+		price: new(big.Int),
+		value: new(big.Int),
+	}
+	gp := new(GasPool).AddGas(1000000)
+
+	if _, _, err := EstimateGas(evm, msg, gp, 0, 100000); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := statedb.GetNonce(sender); got != 5 {
+		t.Fatalf("sender nonce = %d, want 5 (EstimateGas must not leak trial state)", got)
+	}
+}
@@ -18,19 +18,44 @@ package core
 
 import (
 	"errors"
+	"fmt"
 	"math/big"
 
 	"github.com/irchain/go-irchain/common"
+	"github.com/irchain/go-irchain/core/types"
 	"github.com/irchain/go-irchain/core/vm"
 	"github.com/irchain/go-irchain/log"
 	"github.com/irchain/go-irchain/params"
 	"math"
 )
 
+const (
+	// PerAuthBaseCost is the net gas for a valid authorization whose
+	// authority already has code or a non-zero nonce: applyAuthorizations
+	// adds PerEmptyAccountCost-PerAuthBaseCost to the refund counter for it.
+	PerAuthBaseCost = 2500
+	// PerEmptyAccountCost is the intrinsic gas IntrinsicGas charges up front
+	// for every tuple in a SetCodeTx's authorization list, valid or not.
+	PerEmptyAccountCost = 25000
+)
+
 var (
-	errInsufficientGas           = errors.New("insufficient balance to pay for gas")
-	errInsufficientGasByBalance  = errors.New("insufficient balance to pay for gas")
-	errInsufficientGasByContract = errors.New("insufficient balance to pay for gas")
+	// ErrInsufficientFunds is returned if the payer's balance cannot cover
+	// the required gas cost; wrapped with per-case detail so callers can
+	// still match it with errors.Is.
+	ErrInsufficientFunds = errors.New("insufficient balance to pay for gas")
+
+	// ErrFeeCapTooLow is returned if the transaction fee cap is less than the
+	// block's base fee.
+	ErrFeeCapTooLow = errors.New("max fee per gas less than block base fee")
+	// ErrTipAboveFeeCap is returned if the transaction's tip is greater than
+	// its fee cap.
+	ErrTipAboveFeeCap = errors.New("max priority fee per gas higher than max fee per gas")
+
+	// ErrIntrinsicGas is returned if the supplied gas is below the intrinsic
+	// gas required to even start the transaction. EstimateGas treats it as
+	// "raise the lower bound" rather than a hard execution failure.
+	ErrIntrinsicGas = errors.New("intrinsic gas too low")
 )
 
 /*
@@ -69,18 +94,22 @@ type Message interface {
 	To() *common.Address
 
 	GasPrice() *big.Int
+	GasFeeCap() *big.Int
+	GasTipCap() *big.Int
 	Gas() uint64
 	Value() *big.Int
 
 	Nonce() uint64
 	CheckNonce() bool
 	Data() []byte
+	AuthList() types.AuthorizationList
 	// TODO support remark
 	// Remark() []byte
 }
 
-// IntrinsicGas computes the 'intrinsic gas' for a message with the given data.
-func IntrinsicGas(data []byte, contractCreation bool) (uint64, error) {
+// IntrinsicGas computes the 'intrinsic gas' for a message with the given data
+// and authorization-list length (0 for anything but a SetCodeTx).
+func IntrinsicGas(data []byte, contractCreation bool, authListLen int) (uint64, error) {
 	// Set the starting gas for the raw transaction
 	var gas uint64
 	if contractCreation {
@@ -88,6 +117,11 @@ func IntrinsicGas(data []byte, contractCreation bool) (uint64, error) {
 	} else {
 		gas = params.TxGas
 	}
+	// Every authorization tuple, valid or not, is charged up front since
+	// validity can only be known once the authority is recovered.
+	if authListLen > 0 {
+		gas += uint64(authListLen) * PerEmptyAccountCost
+	}
 	// Bump the required gas by the amount of transactional data
 	if len(data) > 0 {
 		// Zero and non-zero bytes are priced differently
@@ -153,27 +187,43 @@ func (st *StateTransition) useGas(amount uint64) error {
 	return nil
 }
 
+// effectiveGasPrice returns the price per unit of gas to charge the payer and
+// credit the miner for. When the block context carries a base fee (EIP-1559),
+// this is min(GasFeeCap, GasTipCap+baseFee); otherwise it is plain GasPrice.
+func (st *StateTransition) effectiveGasPrice() *big.Int {
+	baseFee := st.evm.Context.BaseFee
+	if baseFee == nil {
+		return st.gasPrice
+	}
+	price := new(big.Int).Add(st.msg.GasTipCap(), baseFee)
+	if feeCap := st.msg.GasFeeCap(); price.Cmp(feeCap) > 0 {
+		price = feeCap
+	}
+	return price
+}
+
 // Transactions fee will be deducted from the recipient. Consider the recipient may
 // not have ircer balance, fee will deducted from this transfer.
 func (st *StateTransition) buyGas() error {
 	var (
 		assert *big.Int
-		err    error
+		kind   string
 	)
 	if len(st.data) == 0 {
 		assert = st.value
-		err = errInsufficientGas
+		kind = "value"
 	} else if st.msg.To() == nil {
 		assert = st.state.GetBalance(st.msg.From())
-		err = errInsufficientGasByBalance
+		kind = "sender balance"
 	} else {
-		err = errInsufficientGasByContract
 		assert = st.state.GetBalance(*st.msg.To())
+		kind = "recipient balance"
 	}
-	if assert.Cmp(new(big.Int).Mul(st.gasPrice, new(big.Int).SetUint64(st.msg.Gas()))) < 0 {
-		return err
+	mgval := new(big.Int).Mul(st.effectiveGasPrice(), new(big.Int).SetUint64(st.msg.Gas()))
+	if assert.Cmp(mgval) < 0 {
+		return fmt.Errorf("%w: address %v have %v want %v (%s)", ErrInsufficientFunds, st.msg.From(), assert, mgval, kind)
 	}
-	if err = st.gp.SubGas(st.msg.Gas()); err != nil {
+	if err := st.gp.SubGas(st.msg.Gas()); err != nil {
 		return err
 	}
 
@@ -193,6 +243,18 @@ func (st *StateTransition) preCheck() error {
 			return ErrNonceTooLow
 		}
 	}
+	// When a base fee is active on the block, enforce the EIP-1559 fee-cap
+	// invariants before reserving any gas.
+	if baseFee := st.evm.Context.BaseFee; baseFee != nil {
+		if st.msg.GasFeeCap().Cmp(baseFee) < 0 {
+			return fmt.Errorf("%w: address %v, maxFeePerGas: %s, baseFee: %s",
+				ErrFeeCapTooLow, st.msg.From(), st.msg.GasFeeCap(), baseFee)
+		}
+		if st.msg.GasFeeCap().Cmp(st.msg.GasTipCap()) < 0 {
+			return fmt.Errorf("%w: address %v, maxPriorityFeePerGas: %s, maxFeePerGas: %s",
+				ErrTipAboveFeeCap, st.msg.From(), st.msg.GasTipCap(), st.msg.GasFeeCap())
+		}
+	}
 	return st.buyGas()
 }
 
@@ -206,10 +268,20 @@ func (st *StateTransition) TransitionDb() (ret []byte, usedGas uint64, failed bo
 	}
 
 	// pay intrinsic gas
-	if gas, err := IntrinsicGas(st.data, st.msg.To() == nil); err != nil {
+	authList := st.msg.AuthList()
+	if gas, err := IntrinsicGas(st.data, st.msg.To() == nil, len(authList)); err != nil {
 		return nil, 0, false, err
 	} else if err = st.useGas(gas); err != nil {
-		return nil, 0, false, err
+		// The supplied gas didn't even cover the intrinsic cost; callers
+		// such as EstimateGas treat this as "raise the lower bound", not a
+		// hard execution failure.
+		return nil, 0, false, fmt.Errorf("%w: %v", ErrIntrinsicGas, err)
+	}
+
+	// EIP-7702: apply set-code authorizations after paying for them but
+	// before the EVM runs, so the delegated code is visible to execution.
+	if len(authList) > 0 {
+		st.applyAuthorizations(authList)
 	}
 
 	// do transaction
@@ -220,16 +292,63 @@ func (st *StateTransition) TransitionDb() (ret []byte, usedGas uint64, failed bo
 
 	// refund deposit
 	st.refundGas()
-	st.state.SubBalance(recipient, new(big.Int).Mul(st.gasPrice, new(big.Int).SetUint64(st.gasUsed())))
-	st.state.AddBalance(st.evm.Coinbase, new(big.Int).Mul(st.gasPrice, new(big.Int).SetUint64(st.gasUsed())))
+	gasPrice := st.effectiveGasPrice()
+	st.state.SubBalance(recipient, new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(st.gasUsed())))
+	if baseFee := st.evm.Context.BaseFee; baseFee != nil {
+		// Only the tip goes to the miner; the base-fee portion is burned.
+		tip := new(big.Int).Sub(gasPrice, baseFee)
+		if tip.Sign() < 0 {
+			tip = new(big.Int)
+		}
+		st.state.AddBalance(st.evm.Coinbase, new(big.Int).Mul(tip, new(big.Int).SetUint64(st.gasUsed())))
+	} else {
+		st.state.AddBalance(st.evm.Coinbase, new(big.Int).Mul(gasPrice, new(big.Int).SetUint64(st.gasUsed())))
+	}
 
 	return ret, st.gasUsed(), failed, err
 }
 
+// applyAuthorizations processes a SetCodeTx's authorization list, installing
+// a delegation designator (or clearing one) on each valid authority. Invalid
+// entries - bad signature, wrong chain, stale nonce - are skipped silently;
+// their gas was already charged as part of the intrinsic cost.
+func (st *StateTransition) applyAuthorizations(authList types.AuthorizationList) {
+	chainID := st.evm.ChainConfig().ChainID
+	for _, auth := range authList {
+		authority, err := auth.Authority()
+		if err != nil {
+			continue
+		}
+		if auth.ChainID.Sign() != 0 && auth.ChainID.Cmp(chainID) != 0 {
+			continue
+		}
+		if auth.Nonce != st.state.GetNonce(authority) {
+			continue
+		}
+		// The account already existed; refund the portion of
+		// PerEmptyAccountCost that wasn't needed.
+		if st.state.GetNonce(authority) != 0 || len(st.state.GetCode(authority)) != 0 {
+			st.state.AddRefund(PerEmptyAccountCost - PerAuthBaseCost)
+		}
+		if (auth.Address == common.Address{}) {
+			st.state.SetCode(authority, nil)
+		} else {
+			st.state.SetCode(authority, types.AddressToDelegation(auth.Address))
+		}
+		st.state.SetNonce(authority, auth.Nonce+1)
+	}
+}
+
 // vm errors do not effect consensus and are therefor not
 // assigned to err, except for insufficient balance error.
 func (st *StateTransition) transitionDb() (ret []byte, recipient common.Address, failed bool, err error) {
 	sender := vm.AccountRef(st.msg.From())
+	if pm := st.evm.Precompiles; pm != nil {
+		// Give the manager a chance to take a state snapshot and learn
+		// the sender/recipient before a stateful precompile may run, so it
+		// can read/write state and be rolled back like any other call.
+		pm.Prepare(st.state, vm.MessageContext{Sender: st.msg.From(), To: st.to()})
+	}
 	if st.msg.To() == nil {
 		recipient = sender.Address()
 		ret, _, st.gas, err = st.evm.Create(sender, st.data, st.gas, st.value)
@@ -0,0 +1,137 @@
+// Copyright 2021 The happyuc-go Authors
+// This file is part of the happyuc-go library.
+//
+// The happyuc-go library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The happyuc-go library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the happyuc-go library. If not, see <http://www.gnu.org/licenses/>.
+
+package core
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/irchain/go-irchain/common"
+	"github.com/irchain/go-irchain/core/state"
+	"github.com/irchain/go-irchain/core/types"
+	"github.com/irchain/go-irchain/core/vm"
+	"github.com/irchain/go-irchain/ircdb"
+	"github.com/irchain/go-irchain/params"
+)
+
+// statefulTestPrecompile is a bare-bones stateful precompile used only to
+// prove that transitionDb's Prepare/Run wiring actually reaches a registered
+// stateful precompile through the normal Call path, and rolls its writes
+// back on a reverted message the same as a failed internal call.
+type statefulTestPrecompile struct {
+	slot common.Hash
+	cost uint64
+}
+
+func (p *statefulTestPrecompile) Run(statedb vm.StateDB, msgCtx vm.MessageContext, input []byte, value *big.Int, suppliedGas uint64) ([]byte, uint64, error) {
+	statedb.SetState(msgCtx.To, p.slot, common.BytesToHash(input))
+	if suppliedGas < p.cost {
+		return nil, 0, vm.ErrOutOfGas
+	}
+	return input, suppliedGas - p.cost, nil
+}
+
+// testMessage is a minimal Message for driving TransitionDb directly in tests.
+type testMessage struct {
+	from  common.Address
+	to    *common.Address
+	gas   uint64
+	price *big.Int
+	value *big.Int
+	data  []byte
+}
+
+func (m *testMessage) From() common.Address             { return m.from }
+func (m *testMessage) To() *common.Address              { return m.to }
+func (m *testMessage) GasPrice() *big.Int               { return m.price }
+func (m *testMessage) GasFeeCap() *big.Int              { return m.price }
+func (m *testMessage) GasTipCap() *big.Int              { return m.price }
+func (m *testMessage) Gas() uint64                      { return m.gas }
+func (m *testMessage) Value() *big.Int                  { return m.value }
+func (m *testMessage) Nonce() uint64                    { return 0 }
+func (m *testMessage) CheckNonce() bool                 { return false }
+func (m *testMessage) Data() []byte                     { return m.data }
+func (m *testMessage) AuthList() types.AuthorizationList { return nil }
+
+func newTestTransitionEVM(t *testing.T, pm vm.PrecompileManager) (*vm.EVM, vm.StateDB) {
+	t.Helper()
+	statedb, err := state.New(common.Hash{}, state.NewDatabase(ircdb.NewMemDatabase()))
+	if err != nil {
+		t.Fatalf("failed to create test statedb: %v", err)
+	}
+	ctx := vm.Context{
+		CanTransfer: func(vm.StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(vm.StateDB, common.Address, common.Address, *big.Int) {},
+		GetHash:     func(uint64) common.Hash { return common.Hash{} },
+		Coinbase:    common.HexToAddress("0xc0"),
+		GasLimit:    1000000,
+		BlockNumber: new(big.Int),
+	}
+	evm := vm.NewEVM(ctx, statedb, &params.ChainConfig{ChainID: big.NewInt(1)}, vm.Config{})
+	evm.Precompiles = pm
+	return evm, statedb
+}
+
+func TestTransitionDbRoutesStatefulPrecompile(t *testing.T) {
+	mgr := vm.NewDefaultPrecompileManager(vm.PrecompiledContractsHomestead)
+	precompile := common.HexToAddress("0x0a")
+	slot := common.HexToHash("0x01")
+	mgr.RegisterStateful(precompile, &statefulTestPrecompile{slot: slot, cost: 100})
+
+	evm, statedb := newTestTransitionEVM(t, mgr)
+	msg := &testMessage{
+		from:  common.HexToAddress("0xaa"),
+		to:    &precompile,
+		gas:   100000,
+		price: new(big.Int),
+		value: new(big.Int),
+		data:  []byte{0x2a},
+	}
+	gp := new(GasPool).AddGas(1000000)
+
+	if _, _, failed, err := NewStateTransition(evm, msg, gp).TransitionDb(); err != nil || failed {
+		t.Fatalf("TransitionDb failed unexpectedly: failed=%v err=%v", failed, err)
+	}
+	if want := common.BytesToHash([]byte{0x2a}); statedb.GetState(precompile, slot) != want {
+		t.Fatalf("storage = %x, want %x", statedb.GetState(precompile, slot), want)
+	}
+}
+
+func TestTransitionDbRevertsStatefulPrecompileOnOutOfGas(t *testing.T) {
+	mgr := vm.NewDefaultPrecompileManager(vm.PrecompiledContractsHomestead)
+	precompile := common.HexToAddress("0x0b")
+	slot := common.HexToHash("0x01")
+	mgr.RegisterStateful(precompile, &statefulTestPrecompile{slot: slot, cost: 50000})
+
+	evm, statedb := newTestTransitionEVM(t, mgr)
+	msg := &testMessage{
+		from:  common.HexToAddress("0xaa"),
+		to:    &precompile,
+		gas:   30000,
+		price: new(big.Int),
+		value: new(big.Int),
+		data:  []byte{0x2a},
+	}
+	gp := new(GasPool).AddGas(1000000)
+
+	if _, _, failed, err := NewStateTransition(evm, msg, gp).TransitionDb(); !failed || err != vm.ErrOutOfGas {
+		t.Fatalf("expected a failed transition with ErrOutOfGas when the precompile runs out of gas: failed=%v err=%v", failed, err)
+	}
+	if got := statedb.GetState(precompile, slot); got != (common.Hash{}) {
+		t.Fatalf("storage = %x, want zero value after a reverted run", got)
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright 2021 The happyuc-go Authors
+// This file is part of the happyuc-go library.
+//
+// The happyuc-go library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The happyuc-go library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the happyuc-go library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/irchain/go-irchain/common"
+	"github.com/irchain/go-irchain/crypto"
+)
+
+func TestAddressToDelegationRoundTrip(t *testing.T) {
+	target := common.HexToAddress("0xabcdef0123456789abcdef0123456789abcdef01")
+	code := AddressToDelegation(target)
+
+	got, ok := ParseDelegation(code)
+	if !ok {
+		t.Fatalf("ParseDelegation(%x) = false, want true", code)
+	}
+	if got != target {
+		t.Fatalf("ParseDelegation(%x) = %x, want %x", code, got, target)
+	}
+}
+
+func TestParseDelegationRejectsOrdinaryCode(t *testing.T) {
+	if _, ok := ParseDelegation([]byte{0x60, 0x00, 0x60, 0x00}); ok {
+		t.Fatalf("ParseDelegation should reject code that isn't a delegation designator")
+	}
+	if _, ok := ParseDelegation(nil); ok {
+		t.Fatalf("ParseDelegation should reject empty code")
+	}
+}
+
+func TestAuthorizationAuthority(t *testing.T) {
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	auth := &Authorization{
+		ChainID: big.NewInt(1),
+		Address: common.HexToAddress("0x0b"),
+		Nonce:   0,
+	}
+	sig, err := crypto.Sign(auth.sigHash().Bytes(), key)
+	if err != nil {
+		t.Fatalf("failed to sign authorization: %v", err)
+	}
+	auth.R = new(big.Int).SetBytes(sig[:32])
+	auth.S = new(big.Int).SetBytes(sig[32:64])
+	auth.V = sig[64]
+
+	authority, err := auth.Authority()
+	if err != nil {
+		t.Fatalf("Authority() error: %v", err)
+	}
+	if want := crypto.PubkeyToAddress(key.PublicKey); authority != want {
+		t.Fatalf("Authority() = %x, want %x", authority, want)
+	}
+}
+
+func TestAuthorizationAuthorityMissingSignature(t *testing.T) {
+	auth := &Authorization{ChainID: big.NewInt(1), Address: common.HexToAddress("0x0b")}
+	if _, err := auth.Authority(); err != ErrInvalidSig {
+		t.Fatalf("Authority() error = %v, want ErrInvalidSig", err)
+	}
+}
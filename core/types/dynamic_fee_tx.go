@@ -0,0 +1,116 @@
+// Copyright 2021 The happyuc-go Authors
+// This file is part of the happyuc-go library.
+//
+// The happyuc-go library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The happyuc-go library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the happyuc-go library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"math/big"
+
+	"github.com/irchain/go-irchain/common"
+)
+
+// DynamicFeeTxType is the EIP-1559 envelope type byte. It is prepended to the
+// RLP encoding of a DynamicFeeTx the same way AccessListTxType prefixes an
+// access-list transaction.
+const DynamicFeeTxType = 0x02
+
+// DynamicFeeTx represents an EIP-1559 fee-market transaction. Instead of a
+// single GasPrice, the sender names a tip (GasTipCap) it is willing to pay
+// the miner and a hard ceiling (GasFeeCap) it will never pay more than,
+// letting the protocol-level base fee float between blocks.
+type DynamicFeeTx struct {
+	ChainID    *big.Int
+	Nonce      uint64
+	GasTipCap  *big.Int
+	GasFeeCap  *big.Int
+	Gas        uint64
+	To         *common.Address `rlp:"nil"` // nil means contract creation
+	Value      *big.Int
+	Data       []byte
+	AccessList AccessList
+
+	// Signature values
+	V *big.Int
+	R *big.Int
+	S *big.Int
+}
+
+// copy creates a deep copy of the transaction data and initializes all fields.
+func (tx *DynamicFeeTx) copy() TxData {
+	cpy := &DynamicFeeTx{
+		Nonce: tx.Nonce,
+		To:    copyAddressPtr(tx.To),
+		Data:  common.CopyBytes(tx.Data),
+		Gas:   tx.Gas,
+		// These are copied below.
+		AccessList: make(AccessList, len(tx.AccessList)),
+		Value:      new(big.Int),
+		ChainID:    new(big.Int),
+		GasTipCap:  new(big.Int),
+		GasFeeCap:  new(big.Int),
+		V:          new(big.Int),
+		R:          new(big.Int),
+		S:          new(big.Int),
+	}
+	copy(cpy.AccessList, tx.AccessList)
+	if tx.Value != nil {
+		cpy.Value.Set(tx.Value)
+	}
+	if tx.ChainID != nil {
+		cpy.ChainID.Set(tx.ChainID)
+	}
+	if tx.GasTipCap != nil {
+		cpy.GasTipCap.Set(tx.GasTipCap)
+	}
+	if tx.GasFeeCap != nil {
+		cpy.GasFeeCap.Set(tx.GasFeeCap)
+	}
+	if tx.V != nil {
+		cpy.V.Set(tx.V)
+	}
+	if tx.R != nil {
+		cpy.R.Set(tx.R)
+	}
+	if tx.S != nil {
+		cpy.S.Set(tx.S)
+	}
+	return cpy
+}
+
+// accessors for innerTx.
+func (tx *DynamicFeeTx) txType() byte          { return DynamicFeeTxType }
+func (tx *DynamicFeeTx) chainID() *big.Int     { return tx.ChainID }
+func (tx *DynamicFeeTx) accessList() AccessList { return tx.AccessList }
+func (tx *DynamicFeeTx) data() []byte          { return tx.Data }
+func (tx *DynamicFeeTx) gas() uint64           { return tx.Gas }
+func (tx *DynamicFeeTx) gasFeeCap() *big.Int   { return tx.GasFeeCap }
+func (tx *DynamicFeeTx) gasTipCap() *big.Int   { return tx.GasTipCap }
+
+// gasPrice reports the fee cap for callers that only understand a single
+// price, e.g. legacy RPC fields; the effective price is resolved against the
+// base fee at execution time in core.StateTransition.
+func (tx *DynamicFeeTx) gasPrice() *big.Int  { return tx.GasFeeCap }
+func (tx *DynamicFeeTx) value() *big.Int     { return tx.Value }
+func (tx *DynamicFeeTx) nonce() uint64       { return tx.Nonce }
+func (tx *DynamicFeeTx) to() *common.Address { return tx.To }
+
+func (tx *DynamicFeeTx) rawSignatureValues() (v, r, s *big.Int) {
+	return tx.V, tx.R, tx.S
+}
+
+func (tx *DynamicFeeTx) setSignatureValues(chainID, v, r, s *big.Int) {
+	tx.ChainID, tx.V, tx.R, tx.S = chainID, v, r, s
+}
@@ -0,0 +1,95 @@
+// Copyright 2021 The happyuc-go Authors
+// This file is part of the happyuc-go library.
+//
+// The happyuc-go library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The happyuc-go library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the happyuc-go library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"math/big"
+
+	"github.com/irchain/go-irchain/common"
+	"github.com/irchain/go-irchain/rlp"
+	"golang.org/x/crypto/sha3"
+)
+
+// londonSigner adds DynamicFeeTx support on top of the chain-id-aware
+// signer used for every earlier transaction type.
+type londonSigner struct {
+	EIP155Signer
+}
+
+// NewLondonSigner returns a signer that accepts DynamicFeeTx (EIP-1559)
+// transactions in addition to everything EIP155Signer already handles.
+func NewLondonSigner(chainId *big.Int) Signer {
+	return londonSigner{EIP155Signer: NewEIP155Signer(chainId)}
+}
+
+func (s londonSigner) Sender(tx *Transaction) (common.Address, error) {
+	if tx.Type() != DynamicFeeTxType {
+		return s.EIP155Signer.Sender(tx)
+	}
+	V, R, S := tx.RawSignatureValues()
+	// DynamicFeeTx uses 0/1 parity rather than the EIP-155 27/28 (+chainID) scheme.
+	V = new(big.Int).Add(V, big.NewInt(27))
+	if tx.ChainId().Cmp(s.chainId) != 0 {
+		return common.Address{}, ErrInvalidChainId
+	}
+	return recoverPlain(s.Hash(tx), R, S, V, true)
+}
+
+func (s londonSigner) SignatureValues(tx *Transaction, sig []byte) (R, S, V *big.Int, err error) {
+	if tx.Type() != DynamicFeeTxType {
+		return s.EIP155Signer.SignatureValues(tx, sig)
+	}
+	// Check that chain ID of tx matches the signer. We also accept ID zero here,
+	// because it indicates that the chain ID was not specified in the tx.
+	if tx.ChainId().Sign() != 0 && tx.ChainId().Cmp(s.chainId) != 0 {
+		return nil, nil, nil, ErrInvalidChainId
+	}
+	R, S, _ = decodeSignature(sig)
+	V = big.NewInt(int64(sig[64]))
+	return R, S, V, nil
+}
+
+// Hash returns the hash to be signed by the sender, which does not uniquely
+// identify the transaction.
+func (s londonSigner) Hash(tx *Transaction) common.Hash {
+	if tx.Type() != DynamicFeeTxType {
+		return s.EIP155Signer.Hash(tx)
+	}
+	return prefixedRlpHash(
+		DynamicFeeTxType,
+		[]interface{}{
+			s.chainId,
+			tx.Nonce(),
+			tx.GasTipCap(),
+			tx.GasFeeCap(),
+			tx.Gas(),
+			tx.To(),
+			tx.Value(),
+			tx.Data(),
+			tx.AccessList(),
+		})
+}
+
+// prefixedRlpHash writes typeByte || rlp(data) into a keccak256 sum, the
+// EIP-2718 envelope convention every typed transaction signs over.
+func prefixedRlpHash(typeByte byte, data interface{}) (h common.Hash) {
+	hasher := sha3.NewLegacyKeccak256()
+	hasher.Write([]byte{typeByte})
+	rlp.Encode(hasher, data)
+	hasher.Sum(h[:0])
+	return h
+}
@@ -0,0 +1,233 @@
+// Copyright 2021 The happyuc-go Authors
+// This file is part of the happyuc-go library.
+//
+// The happyuc-go library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The happyuc-go library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the happyuc-go library. If not, see <http://www.gnu.org/licenses/>.
+
+package types
+
+import (
+	"math/big"
+
+	"github.com/irchain/go-irchain/common"
+)
+
+// SetCodeTxType is the EIP-7702 envelope type byte.
+const SetCodeTxType = 0x04
+
+// delegationPrefix marks an account's code as a delegation designator rather
+// than ordinary bytecode: 0xef0100 || address.
+var delegationPrefix = []byte{0xef, 0x01, 0x00}
+
+// AddressToDelegation returns the 23-byte delegation designator that
+// authorization processing installs as an authority's code.
+func AddressToDelegation(addr common.Address) []byte {
+	return append(append([]byte{}, delegationPrefix...), addr.Bytes()...)
+}
+
+// ParseDelegation returns the delegated address and true if code is a
+// delegation designator, or the zero address and false otherwise.
+func ParseDelegation(code []byte) (common.Address, bool) {
+	if len(code) != len(delegationPrefix)+common.AddressLength {
+		return common.Address{}, false
+	}
+	for i, b := range delegationPrefix {
+		if code[i] != b {
+			return common.Address{}, false
+		}
+	}
+	return common.BytesToAddress(code[len(delegationPrefix):]), true
+}
+
+// Authorization is a single entry of a SetCodeTx's authorization list: a
+// signed statement by an EOA that its code should delegate to Address.
+type Authorization struct {
+	ChainID *big.Int
+	Address common.Address
+	Nonce   uint64
+
+	// Signature values
+	V uint8
+	R *big.Int
+	S *big.Int
+}
+
+// AuthorizationList is the list of authorizations carried by a SetCodeTx.
+type AuthorizationList []Authorization
+
+// sigHash returns the hash the authority signs: keccak(0x05 || rlp([chainID, address, nonce])).
+func (a *Authorization) sigHash() common.Hash {
+	return prefixedRlpHash(0x05, []interface{}{a.ChainID, a.Address, a.Nonce})
+}
+
+// Authority recovers the account that signed this authorization.
+func (a *Authorization) Authority() (common.Address, error) {
+	if a.R == nil || a.S == nil {
+		return common.Address{}, ErrInvalidSig
+	}
+	// Authorization.V uses 0/1 parity rather than the EIP-155 27/28 scheme,
+	// same as pragueSigner.Sender below.
+	V := new(big.Int).Add(new(big.Int).SetUint64(uint64(a.V)), big.NewInt(27))
+	return recoverPlain(a.sigHash(), a.R, a.S, V, true)
+}
+
+// SetCodeTx represents an EIP-7702 set-code transaction: a regular
+// dynamic-fee transaction that also carries a list of authorizations, each
+// installing a delegation designator as an EOA's code; the designator stays
+// in place after the transaction.
+type SetCodeTx struct {
+	ChainID    *big.Int
+	Nonce      uint64
+	GasTipCap  *big.Int
+	GasFeeCap  *big.Int
+	Gas        uint64
+	To         *common.Address `rlp:"nil"`
+	Value      *big.Int
+	Data       []byte
+	AccessList AccessList
+	AuthList   AuthorizationList
+
+	// Signature values
+	V *big.Int
+	R *big.Int
+	S *big.Int
+}
+
+func (tx *SetCodeTx) copy() TxData {
+	cpy := &SetCodeTx{
+		Nonce:      tx.Nonce,
+		To:         copyAddressPtr(tx.To),
+		Data:       common.CopyBytes(tx.Data),
+		Gas:        tx.Gas,
+		AccessList: make(AccessList, len(tx.AccessList)),
+		AuthList:   make(AuthorizationList, len(tx.AuthList)),
+		Value:      new(big.Int),
+		ChainID:    new(big.Int),
+		GasTipCap:  new(big.Int),
+		GasFeeCap:  new(big.Int),
+		V:          new(big.Int),
+		R:          new(big.Int),
+		S:          new(big.Int),
+	}
+	copy(cpy.AccessList, tx.AccessList)
+	copy(cpy.AuthList, tx.AuthList)
+	if tx.Value != nil {
+		cpy.Value.Set(tx.Value)
+	}
+	if tx.ChainID != nil {
+		cpy.ChainID.Set(tx.ChainID)
+	}
+	if tx.GasTipCap != nil {
+		cpy.GasTipCap.Set(tx.GasTipCap)
+	}
+	if tx.GasFeeCap != nil {
+		cpy.GasFeeCap.Set(tx.GasFeeCap)
+	}
+	if tx.V != nil {
+		cpy.V.Set(tx.V)
+	}
+	if tx.R != nil {
+		cpy.R.Set(tx.R)
+	}
+	if tx.S != nil {
+		cpy.S.Set(tx.S)
+	}
+	return cpy
+}
+
+func (tx *SetCodeTx) txType() byte                         { return SetCodeTxType }
+func (tx *SetCodeTx) chainID() *big.Int                    { return tx.ChainID }
+func (tx *SetCodeTx) accessList() AccessList                { return tx.AccessList }
+func (tx *SetCodeTx) authorizationList() AuthorizationList { return tx.AuthList }
+func (tx *SetCodeTx) data() []byte                         { return tx.Data }
+func (tx *SetCodeTx) gas() uint64                           { return tx.Gas }
+func (tx *SetCodeTx) gasFeeCap() *big.Int                   { return tx.GasFeeCap }
+func (tx *SetCodeTx) gasTipCap() *big.Int                   { return tx.GasTipCap }
+func (tx *SetCodeTx) gasPrice() *big.Int                    { return tx.GasFeeCap }
+func (tx *SetCodeTx) value() *big.Int                       { return tx.Value }
+func (tx *SetCodeTx) nonce() uint64                         { return tx.Nonce }
+func (tx *SetCodeTx) to() *common.Address                   { return tx.To }
+
+func (tx *SetCodeTx) rawSignatureValues() (v, r, s *big.Int) {
+	return tx.V, tx.R, tx.S
+}
+
+func (tx *SetCodeTx) setSignatureValues(chainID, v, r, s *big.Int) {
+	tx.ChainID, tx.V, tx.R, tx.S = chainID, v, r, s
+}
+
+// hash for signing is identical in shape to DynamicFeeTx, plus the
+// authorization list, per EIP-7702.
+func setCodeTxSigningHash(chainID *big.Int, tx *Transaction) common.Hash {
+	return prefixedRlpHash(
+		SetCodeTxType,
+		[]interface{}{
+			chainID,
+			tx.Nonce(),
+			tx.GasTipCap(),
+			tx.GasFeeCap(),
+			tx.Gas(),
+			tx.To(),
+			tx.Value(),
+			tx.Data(),
+			tx.AccessList(),
+			tx.AuthorizationList(),
+		})
+}
+
+// pragueSigner adds SetCodeTx (EIP-7702) support on top of londonSigner.
+type pragueSigner struct {
+	londonSigner
+}
+
+// NewPragueSigner returns a signer that accepts SetCodeTx (EIP-7702)
+// transactions in addition to everything londonSigner already handles.
+func NewPragueSigner(chainId *big.Int) Signer {
+	return pragueSigner{londonSigner: londonSigner{EIP155Signer: NewEIP155Signer(chainId)}}
+}
+
+func (s pragueSigner) Sender(tx *Transaction) (common.Address, error) {
+	if tx.Type() != SetCodeTxType {
+		return s.londonSigner.Sender(tx)
+	}
+	V, R, S := tx.RawSignatureValues()
+	// SetCodeTx uses 0/1 parity rather than the EIP-155 27/28 (+chainID) scheme.
+	V = new(big.Int).Add(V, big.NewInt(27))
+	if tx.ChainId().Cmp(s.chainId) != 0 {
+		return common.Address{}, ErrInvalidChainId
+	}
+	return recoverPlain(s.Hash(tx), R, S, V, true)
+}
+
+func (s pragueSigner) SignatureValues(tx *Transaction, sig []byte) (R, S, V *big.Int, err error) {
+	if tx.Type() != SetCodeTxType {
+		return s.londonSigner.SignatureValues(tx, sig)
+	}
+	// Check that chain ID of tx matches the signer. We also accept ID zero
+	// here, because it indicates that the chain ID was not specified in the tx.
+	if tx.ChainId().Sign() != 0 && tx.ChainId().Cmp(s.chainId) != 0 {
+		return nil, nil, nil, ErrInvalidChainId
+	}
+	R, S, _ = decodeSignature(sig)
+	V = big.NewInt(int64(sig[64]))
+	return R, S, V, nil
+}
+
+// Hash returns the hash to be signed by the sender, which does not uniquely
+// identify the transaction.
+func (s pragueSigner) Hash(tx *Transaction) common.Hash {
+	if tx.Type() != SetCodeTxType {
+		return s.londonSigner.Hash(tx)
+	}
+	return setCodeTxSigningHash(s.chainId, tx)
+}
@@ -0,0 +1,62 @@
+// Copyright 2014 The go-irchain Authors
+// This file is part of the go-irchain library.
+//
+// The go-irchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-irchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-irchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"math/big"
+
+	"github.com/irchain/go-irchain/common"
+)
+
+// CanTransferFunc is the signature of a transfer guard function.
+type CanTransferFunc func(StateDB, common.Address, *big.Int) bool
+
+// TransferFunc is the signature of a transfer function.
+type TransferFunc func(StateDB, common.Address, common.Address, *big.Int)
+
+// GetHashFunc returns the nth block hash in the blockchain and is used by
+// the BLOCKHASH EVM op code.
+type GetHashFunc func(uint64) common.Hash
+
+// Context provides the EVM with auxiliary information. Once provided it
+// shouldn't be modified.
+type Context struct {
+	// CanTransfer returns whether the account contains sufficient ircer to
+	// transfer the value.
+	CanTransfer CanTransferFunc
+	// Transfer transfers ircer from one account to the other.
+	Transfer TransferFunc
+	// GetHash returns the hash corresponding to n.
+	GetHash GetHashFunc
+
+	// Message information
+	Origin   common.Address // Provides information for ORIGIN
+	GasPrice *big.Int       // Provides information for GASPRICE
+
+	// Block information
+	Coinbase    common.Address // Provides information for COINBASE
+	GasLimit    uint64         // Provides information for GASLIMIT
+	BlockNumber *big.Int       // Provides information for NUMBER
+	Time        *big.Int       // Provides information for TIME
+	Difficulty  *big.Int       // Provides information for DIFFICULTY
+
+	// BaseFee is the block's EIP-1559 base fee per gas, or nil on a
+	// chain/block that predates the fork. core.StateTransition's
+	// effectiveGasPrice and the miner fee split read it directly off the
+	// context instead of threading it through as a separate argument.
+	BaseFee *big.Int
+}
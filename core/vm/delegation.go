@@ -0,0 +1,34 @@
+// Copyright 2021 The happyuc-go Authors
+// This file is part of the happyuc-go library.
+//
+// The happyuc-go library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The happyuc-go library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the happyuc-go library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"github.com/irchain/go-irchain/common"
+	"github.com/irchain/go-irchain/core/types"
+)
+
+// resolveCode returns the code the EVM should actually execute for addr:
+// the account's own code, unless that code is an EIP-7702 delegation
+// designator, in which case it's the designated address's code. Storage
+// always stays rooted at addr regardless of delegation.
+func resolveCode(state StateDB, addr common.Address) []byte {
+	code := state.GetCode(addr)
+	if target, ok := types.ParseDelegation(code); ok {
+		return state.GetCode(target)
+	}
+	return code
+}
@@ -0,0 +1,54 @@
+// Copyright 2021 The happyuc-go Authors
+// This file is part of the happyuc-go library.
+//
+// The happyuc-go library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The happyuc-go library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the happyuc-go library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"testing"
+
+	"github.com/irchain/go-irchain/common"
+	"github.com/irchain/go-irchain/core/state"
+	"github.com/irchain/go-irchain/core/types"
+	"github.com/irchain/go-irchain/ircdb"
+)
+
+func TestResolveCodeNoDelegation(t *testing.T) {
+	statedb, err := state.New(common.Hash{}, state.NewDatabase(ircdb.NewMemDatabase()))
+	if err != nil {
+		t.Fatalf("failed to create test statedb: %v", err)
+	}
+	addr := common.HexToAddress("0x0a")
+	statedb.SetCode(addr, []byte{0x60, 0x00})
+
+	if got := resolveCode(statedb, addr); string(got) != string([]byte{0x60, 0x00}) {
+		t.Fatalf("resolveCode = %x, want %x", got, []byte{0x60, 0x00})
+	}
+}
+
+func TestResolveCodeFollowsDelegation(t *testing.T) {
+	statedb, err := state.New(common.Hash{}, state.NewDatabase(ircdb.NewMemDatabase()))
+	if err != nil {
+		t.Fatalf("failed to create test statedb: %v", err)
+	}
+	authority := common.HexToAddress("0x0a")
+	target := common.HexToAddress("0x0b")
+	statedb.SetCode(authority, types.AddressToDelegation(target))
+	statedb.SetCode(target, []byte{0x60, 0x01})
+
+	if got := resolveCode(statedb, authority); string(got) != string([]byte{0x60, 0x01}) {
+		t.Fatalf("resolveCode = %x, want the delegated target's code %x", got, []byte{0x60, 0x01})
+	}
+}
@@ -0,0 +1,237 @@
+// Copyright 2014 The go-irchain Authors
+// This file is part of the go-irchain library.
+//
+// The go-irchain library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-irchain library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-irchain library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"math/big"
+	"sync/atomic"
+
+	"github.com/irchain/go-irchain/common"
+	"github.com/irchain/go-irchain/crypto"
+	"github.com/irchain/go-irchain/params"
+)
+
+// EVM is the Ethereum Virtual Machine base object and provides the
+// necessary tools to run a contract on the given state with the provided
+// context. It should be noted that any error generated through any of the
+// calls should be considered a revert-state-and-consume-all-gas operation;
+// no checks on specific errors should ever be performed. The interpreter
+// makes sure that any errors generated are to be considered faulty code.
+//
+// The EVM should never be reused and is not thread safe.
+type EVM struct {
+	// Context provides auxiliary blockchain related information.
+	Context
+	// StateDB gives access to the underlying state.
+	StateDB StateDB
+	// depth is the current call stack.
+	depth int
+
+	// chainConfig contains information about the current chain.
+	chainConfig *params.ChainConfig
+	// vmConfig are the virtual machine configuration options used to
+	// initialise the evm.
+	vmConfig Config
+	// interpreter is the global (to this context) irchain virtual machine
+	// used throughout the execution of the tx.
+	interpreter *Interpreter
+	// abort is used to abort the EVM calling operations.
+	abort int32
+
+	// Precompiles, when non-nil, takes over resolving and running
+	// precompiled-contract calls instead of the hard-coded
+	// PrecompiledContractsHomestead table - see precompile_manager.go. Chain
+	// integrators install it to register stateful precompiles.
+	Precompiles PrecompileManager
+}
+
+// NewEVM returns a new EVM. The returned EVM is not thread safe and should
+// only ever be used *once*.
+func NewEVM(ctx Context, statedb StateDB, chainConfig *params.ChainConfig, vmConfig Config) *EVM {
+	evm := &EVM{
+		Context:     ctx,
+		StateDB:     statedb,
+		vmConfig:    vmConfig,
+		chainConfig: chainConfig,
+	}
+	evm.interpreter = NewInterpreter(evm, vmConfig)
+	return evm
+}
+
+// Cancel cancels any running EVM operation. This may be called concurrently
+// and it's safe to be called multiple times.
+func (evm *EVM) Cancel() {
+	atomic.StoreInt32(&evm.abort, 1)
+}
+
+// Cancelled returns true if Cancel has been called.
+func (evm *EVM) Cancelled() bool {
+	return atomic.LoadInt32(&evm.abort) == 1
+}
+
+// ChainConfig returns the environment's chain configuration.
+func (evm *EVM) ChainConfig() *params.ChainConfig {
+	return evm.chainConfig
+}
+
+// Interpreter returns the current interpreter.
+func (evm *EVM) Interpreter() *Interpreter {
+	return evm.interpreter
+}
+
+// precompile returns the precompiled contract at addr and whether one
+// exists, consulting the pluggable PrecompileManager when one is installed
+// and falling back to the hard-coded Homestead table otherwise.
+func (evm *EVM) precompile(addr common.Address) (PrecompiledContract, bool) {
+	if evm.Precompiles != nil {
+		if !evm.Precompiles.Has(addr) {
+			return nil, false
+		}
+		return evm.Precompiles.Get(addr), true
+	}
+	p, ok := PrecompiledContractsHomestead[addr]
+	return p, ok
+}
+
+// runPrecompile dispatches to the stateful PrecompileManager when one is
+// installed, otherwise runs the stateless PrecompiledContract directly,
+// charging its RequiredGas quote up front.
+func (evm *EVM) runPrecompile(p PrecompiledContract, caller ContractRef, addr common.Address, input []byte, value *big.Int, gas uint64) ([]byte, uint64, error) {
+	if evm.Precompiles != nil {
+		evm.Precompiles.Prepare(evm.StateDB, MessageContext{Sender: caller.Address(), To: addr})
+		return evm.Precompiles.Run(addr, evm.StateDB, input, value, gas)
+	}
+	gasCost := p.RequiredGas(input)
+	if gas < gasCost {
+		return nil, 0, ErrOutOfGas
+	}
+	ret, err := p.Run(input)
+	return ret, gas - gasCost, err
+}
+
+// Call executes the contract associated with addr with the given input as
+// parameters. It also handles any necessary value transfer required and
+// takes the necessary steps to create accounts and reverses the state in
+// case of an execution error or failed value transfer.
+func (evm *EVM) Call(caller ContractRef, addr common.Address, input []byte, gas uint64, value *big.Int) (ret []byte, leftOverGas uint64, err error) {
+	if evm.depth > int(params.CallCreateDepth) {
+		return nil, gas, ErrDepth
+	}
+	if !evm.Context.CanTransfer(evm.StateDB, caller.Address(), value) {
+		return nil, gas, ErrInsufficientBalance
+	}
+	var (
+		to       = AccountRef(addr)
+		snapshot = evm.StateDB.Snapshot()
+	)
+	p, isPrecompile := evm.precompile(addr)
+	if !evm.StateDB.Exist(addr) {
+		if !isPrecompile && value.Sign() == 0 {
+			return nil, gas, nil
+		}
+		evm.StateDB.CreateAccount(addr)
+	}
+	// Transfer runs regardless of whether addr is a precompile - precompile
+	// addresses are ordinary accounts and a CALL with value > 0 to one still
+	// has to move the balance.
+	evm.Context.Transfer(evm.StateDB, caller.Address(), to.Address(), value)
+
+	if isPrecompile {
+		ret, gas, err = evm.runPrecompile(p, caller, addr, input, value, gas)
+	} else {
+		// Follow the EIP-7702 delegation designator, if any, when loading the
+		// code to execute - storage always stays rooted at addr regardless.
+		code := resolveCode(evm.StateDB, addr)
+		if len(code) == 0 {
+			ret, err = nil, nil
+		} else {
+			contract := NewContract(caller, to, value, gas)
+			contract.SetCallCode(&addr, evm.StateDB.GetCodeHash(addr), code)
+			ret, err = evm.interpreter.Run(contract, input, false)
+			gas = contract.Gas
+		}
+	}
+	if err != nil {
+		evm.StateDB.RevertToSnapshot(snapshot)
+		if err != ErrExecutionReverted {
+			gas = 0
+		}
+	}
+	return ret, gas, err
+}
+
+// StaticCall executes the contract associated with addr with the given
+// input as parameters while disallowing any modifications to the state
+// during the call. Opcodes that attempt such modifications result in
+// exceptions instead of being applied.
+func (evm *EVM) StaticCall(caller ContractRef, addr common.Address, input []byte, gas uint64) (ret []byte, leftOverGas uint64, err error) {
+	if evm.depth > int(params.CallCreateDepth) {
+		return nil, gas, ErrDepth
+	}
+	var (
+		to       = AccountRef(addr)
+		snapshot = evm.StateDB.Snapshot()
+	)
+	if p, isPrecompile := evm.precompile(addr); isPrecompile {
+		ret, gas, err = evm.runPrecompile(p, caller, addr, input, new(big.Int), gas)
+	} else {
+		// Same delegation-following code lookup as Call; a delegated EOA's
+		// code is read-only here regardless.
+		code := resolveCode(evm.StateDB, addr)
+		contract := NewContract(caller, to, new(big.Int), gas)
+		contract.SetCallCode(&addr, evm.StateDB.GetCodeHash(addr), code)
+		ret, err = evm.interpreter.Run(contract, input, true)
+		gas = contract.Gas
+	}
+	if err != nil {
+		evm.StateDB.RevertToSnapshot(snapshot)
+		gas = 0
+	}
+	return ret, gas, err
+}
+
+// Create creates a new contract using code as deployment code.
+func (evm *EVM) Create(caller ContractRef, code []byte, gas uint64, value *big.Int) (ret []byte, contractAddr common.Address, leftOverGas uint64, err error) {
+	if evm.depth > int(params.CallCreateDepth) {
+		return nil, common.Address{}, gas, ErrDepth
+	}
+	if !evm.Context.CanTransfer(evm.StateDB, caller.Address(), value) {
+		return nil, common.Address{}, gas, ErrInsufficientBalance
+	}
+	nonce := evm.StateDB.GetNonce(caller.Address())
+	evm.StateDB.SetNonce(caller.Address(), nonce+1)
+
+	contractAddr = crypto.CreateAddress(caller.Address(), nonce)
+	snapshot := evm.StateDB.Snapshot()
+	evm.StateDB.CreateAccount(contractAddr)
+	evm.Context.Transfer(evm.StateDB, caller.Address(), contractAddr, value)
+
+	contract := NewContract(caller, AccountRef(contractAddr), value, gas)
+	contract.SetCallCode(&contractAddr, crypto.Keccak256Hash(code), code)
+
+	ret, err = evm.interpreter.Run(contract, nil, false)
+	if err == nil && len(ret) > 0 {
+		evm.StateDB.SetCode(contractAddr, ret)
+	}
+	if err != nil {
+		evm.StateDB.RevertToSnapshot(snapshot)
+		if err != ErrExecutionReverted {
+			contract.Gas = 0
+		}
+	}
+	return ret, contractAddr, contract.Gas, err
+}
@@ -0,0 +1,70 @@
+// Copyright 2021 The happyuc-go Authors
+// This file is part of the happyuc-go library.
+//
+// The happyuc-go library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The happyuc-go library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the happyuc-go library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/irchain/go-irchain/common"
+	"github.com/irchain/go-irchain/core/state"
+	"github.com/irchain/go-irchain/ircdb"
+	"github.com/irchain/go-irchain/params"
+)
+
+func newTestEVM(t *testing.T) (*EVM, StateDB) {
+	t.Helper()
+	statedb, err := state.New(common.Hash{}, state.NewDatabase(ircdb.NewMemDatabase()))
+	if err != nil {
+		t.Fatalf("failed to create test statedb: %v", err)
+	}
+	ctx := Context{
+		CanTransfer: func(StateDB, common.Address, *big.Int) bool { return true },
+		Transfer:    func(StateDB, common.Address, common.Address, *big.Int) {},
+		GetHash:     func(uint64) common.Hash { return common.Hash{} },
+		GasLimit:    1000000,
+		BlockNumber: new(big.Int),
+	}
+	return NewEVM(ctx, statedb, &params.ChainConfig{ChainID: big.NewInt(1)}, Config{}), statedb
+}
+
+// TestEVMCallRoutesStatefulPrecompile checks that Call, not just a direct
+// PrecompileManager.Run, reaches a registered stateful precompile - the
+// integration path core.StateTransition.transitionDb actually exercises.
+func TestEVMCallRoutesStatefulPrecompile(t *testing.T) {
+	evm, statedb := newTestEVM(t)
+	mgr := NewDefaultPrecompileManager(PrecompiledContractsHomestead)
+	addr := common.HexToAddress("0x0a")
+	slot := common.HexToHash("0x01")
+	mgr.RegisterStateful(addr, &storagePrecompile{slot: slot, cost: 100})
+	evm.Precompiles = mgr
+
+	caller := AccountRef(common.HexToAddress("0xaa"))
+	ret, gasLeft, err := evm.Call(caller, addr, []byte{0x2a}, 1000, new(big.Int))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gasLeft != 900 {
+		t.Fatalf("gas left = %d, want 900", gasLeft)
+	}
+	if got := string(ret); got != string([]byte{0x2a}) {
+		t.Fatalf("return data = %x, want %x", ret, []byte{0x2a})
+	}
+	if want := common.BytesToHash([]byte{0x2a}); statedb.GetState(addr, slot) != want {
+		t.Fatalf("storage = %x, want %x", statedb.GetState(addr, slot), want)
+	}
+}
@@ -0,0 +1,120 @@
+// Copyright 2021 The happyuc-go Authors
+// This file is part of the happyuc-go library.
+//
+// The happyuc-go library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The happyuc-go library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the happyuc-go library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"math/big"
+
+	"github.com/irchain/go-irchain/common"
+)
+
+// MessageContext carries the pieces of the in-flight message that a
+// stateful precompile needs but Run's own argument list doesn't expose.
+type MessageContext struct {
+	Sender common.Address
+	To     common.Address
+}
+
+// StatefulPrecompiledContract is a precompile that, unlike the built-in
+// PrecompiledContract set, needs access to the calling context and world
+// state - e.g. to read or write storage - and consumes gas through the
+// normal accounting path rather than a single RequiredGas() quote.
+type StatefulPrecompiledContract interface {
+	Run(statedb StateDB, msgCtx MessageContext, input []byte, value *big.Int, suppliedGas uint64) (ret []byte, gasLeft uint64, err error)
+}
+
+// PrecompileManager abstracts how the EVM resolves and runs precompiled
+// contracts, so chain integrators can register stateful precompiles
+// alongside the built-in stateless tables instead of the hard-coded
+// PrecompiledContracts* maps.
+type PrecompileManager interface {
+	// Has reports whether addr names a precompile under the currently
+	// active rule set.
+	Has(addr common.Address) bool
+	// Get returns the stateless precompiled contract at addr, if any.
+	Get(addr common.Address) PrecompiledContract
+	// Prepare takes a state snapshot and records the current message's
+	// sender/recipient, ahead of a Run that a stateful precompile may need
+	// to unwind on revert.
+	Prepare(statedb StateDB, msgCtx MessageContext)
+	// Run executes the precompile at addr - stateful or built-in - and
+	// returns its output, the gas left over, and any error.
+	Run(addr common.Address, statedb StateDB, input []byte, value *big.Int, suppliedGas uint64) (ret []byte, gasLeft uint64, err error)
+}
+
+// defaultPrecompileManager wraps one of the built-in PrecompiledContracts*
+// tables and layers optional stateful precompiles on top of it.
+type defaultPrecompileManager struct {
+	builtin  map[common.Address]PrecompiledContract
+	stateful map[common.Address]StatefulPrecompiledContract
+
+	snapshot int
+	msgCtx   MessageContext
+}
+
+// NewDefaultPrecompileManager returns a PrecompileManager backed by table,
+// the built-in precompile set selected for the EVM's active fork (one of
+// the PrecompiledContractsHomestead/Byzantium/... maps).
+func NewDefaultPrecompileManager(table map[common.Address]PrecompiledContract) *defaultPrecompileManager {
+	return &defaultPrecompileManager{
+		builtin:  table,
+		stateful: make(map[common.Address]StatefulPrecompiledContract),
+	}
+}
+
+// RegisterStateful installs a stateful precompile at addr, shadowing any
+// built-in contract already at that address.
+func (m *defaultPrecompileManager) RegisterStateful(addr common.Address, c StatefulPrecompiledContract) {
+	m.stateful[addr] = c
+}
+
+func (m *defaultPrecompileManager) Has(addr common.Address) bool {
+	if _, ok := m.stateful[addr]; ok {
+		return true
+	}
+	_, ok := m.builtin[addr]
+	return ok
+}
+
+func (m *defaultPrecompileManager) Get(addr common.Address) PrecompiledContract {
+	return m.builtin[addr]
+}
+
+func (m *defaultPrecompileManager) Prepare(statedb StateDB, msgCtx MessageContext) {
+	m.snapshot = statedb.Snapshot()
+	m.msgCtx = msgCtx
+}
+
+func (m *defaultPrecompileManager) Run(addr common.Address, statedb StateDB, input []byte, value *big.Int, suppliedGas uint64) (ret []byte, gasLeft uint64, err error) {
+	if c, ok := m.stateful[addr]; ok {
+		ret, gasLeft, err = c.Run(statedb, m.msgCtx, input, value, suppliedGas)
+		if err != nil {
+			statedb.RevertToSnapshot(m.snapshot)
+		}
+		return ret, gasLeft, err
+	}
+	c, ok := m.builtin[addr]
+	if !ok {
+		return nil, suppliedGas, ErrExecutionReverted
+	}
+	gasCost := c.RequiredGas(input)
+	if suppliedGas < gasCost {
+		return nil, 0, ErrOutOfGas
+	}
+	ret, err = c.Run(input)
+	return ret, suppliedGas - gasCost, err
+}
@@ -0,0 +1,95 @@
+// Copyright 2021 The happyuc-go Authors
+// This file is part of the happyuc-go library.
+//
+// The happyuc-go library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The happyuc-go library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the happyuc-go library. If not, see <http://www.gnu.org/licenses/>.
+
+package vm
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/irchain/go-irchain/common"
+	"github.com/irchain/go-irchain/core/state"
+	"github.com/irchain/go-irchain/ircdb"
+)
+
+// storagePrecompile is a sample stateful precompile: it writes its input
+// into a fixed storage slot under its own address, then succeeds or fails
+// depending on whether it was given enough gas.
+type storagePrecompile struct {
+	slot common.Hash
+	cost uint64
+}
+
+func (p *storagePrecompile) Run(statedb StateDB, msgCtx MessageContext, input []byte, value *big.Int, suppliedGas uint64) ([]byte, uint64, error) {
+	statedb.SetState(msgCtx.To, p.slot, common.BytesToHash(input))
+	if suppliedGas < p.cost {
+		return nil, 0, ErrOutOfGas
+	}
+	return input, suppliedGas - p.cost, nil
+}
+
+func newTestManager(t *testing.T) (*defaultPrecompileManager, StateDB) {
+	t.Helper()
+	statedb, err := state.New(common.Hash{}, state.NewDatabase(ircdb.NewMemDatabase()))
+	if err != nil {
+		t.Fatalf("failed to create test statedb: %v", err)
+	}
+	return NewDefaultPrecompileManager(PrecompiledContractsHomestead), statedb
+}
+
+func TestPrecompileManagerStatefulRun(t *testing.T) {
+	mgr, statedb := newTestManager(t)
+	addr := common.HexToAddress("0x0a")
+	slot := common.HexToHash("0x01")
+	mgr.RegisterStateful(addr, &storagePrecompile{slot: slot, cost: 100})
+
+	if !mgr.Has(addr) {
+		t.Fatalf("expected manager to report the registered precompile as present")
+	}
+
+	mgr.Prepare(statedb, MessageContext{Sender: common.HexToAddress("0xaa"), To: addr})
+	ret, gasLeft, err := mgr.Run(addr, statedb, []byte{0x2a}, new(big.Int), 1000)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if gasLeft != 900 {
+		t.Fatalf("gas left = %d, want 900", gasLeft)
+	}
+	if want := common.BytesToHash([]byte{0x2a}); statedb.GetState(addr, slot) != want {
+		t.Fatalf("storage = %x, want %x", statedb.GetState(addr, slot), want)
+	}
+	if got := string(ret); got != string([]byte{0x2a}) {
+		t.Fatalf("return data = %x, want %x", ret, []byte{0x2a})
+	}
+}
+
+// TestPrecompileManagerStatefulRevert checks that a stateful precompile's
+// storage writes are rolled back to the Prepare-time snapshot when it
+// returns an error, the same as a reverted internal call would be.
+func TestPrecompileManagerStatefulRevert(t *testing.T) {
+	mgr, statedb := newTestManager(t)
+	addr := common.HexToAddress("0x0b")
+	slot := common.HexToHash("0x01")
+	mgr.RegisterStateful(addr, &storagePrecompile{slot: slot, cost: 5000})
+
+	mgr.Prepare(statedb, MessageContext{Sender: common.HexToAddress("0xaa"), To: addr})
+	if _, _, err := mgr.Run(addr, statedb, []byte{0x2a}, new(big.Int), 10); err != ErrOutOfGas {
+		t.Fatalf("err = %v, want ErrOutOfGas", err)
+	}
+	if got := statedb.GetState(addr, slot); got != (common.Hash{}) {
+		t.Fatalf("storage = %x, want zero value after a reverted run", got)
+	}
+}
@@ -55,6 +55,7 @@ type callContext struct {
 	Difficulty *math.HexOrDecimal256 `json:"difficulty"`
 	Time       math.HexOrDecimal64   `json:"timestamp"`
 	GasLimit   math.HexOrDecimal64   `json:"gasLimit"`
+	BaseFee    *math.HexOrDecimal256 `json:"baseFee,omitempty"`
 	Miner      common.Address        `json:"miner"`
 }
 
@@ -108,6 +109,7 @@ func TestCallTracer(t *testing.T) {
 				Difficulty:  (*big.Int)(test.Context.Difficulty),
 				GasLimit:    uint64(test.Context.GasLimit),
 				GasPrice:    tx.GasPrice(),
+				BaseFee:     (*big.Int)(test.Context.BaseFee),
 			}
 			statedb := tests.MakePreState(ircdb.NewMemDatabase(), test.Genesis.Alloc)
 
@@ -0,0 +1,227 @@
+// Copyright 2021 The happyuc-go Authors
+// This file is part of the happyuc-go library.
+//
+// The happyuc-go library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The happyuc-go library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the happyuc-go library. If not, see <http://www.gnu.org/licenses/>.
+
+package miner
+
+import (
+	"errors"
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/irchain/go-irchain/common"
+	"github.com/irchain/go-irchain/common/hexutil"
+	"github.com/irchain/go-irchain/consensus"
+	"github.com/irchain/go-irchain/consensus/ethash"
+	"github.com/irchain/go-irchain/core/types"
+	"github.com/irchain/go-irchain/log"
+)
+
+// staleJobBlocks is how many block intervals a cached job is kept around
+// for before it is dropped as stale.
+const staleJobBlocks = 7
+
+// jobExpiry is how long a cached job is kept before staleJobBlocks' worth
+// of blocks have certainly passed it by.
+const jobExpiry = staleJobBlocks * 12 * time.Second
+
+// hashrateReport is one external miner's most recently reported hashrate,
+// along with when it last pinged so stale reporters can be dropped.
+type hashrateReport struct {
+	ping time.Time
+	rate uint64
+}
+
+// RemoteAgent implements Agent like CpuAgent, but instead of sealing blocks
+// itself it exposes pending work over a stratum-style RPC job protocol:
+// external miners poll irc_getWork for [headerHash, seedHash, target],
+// mine locally, then call irc_submitWork with their solution and
+// irc_submitHashrate to report throughput.
+type RemoteAgent struct {
+	mu sync.Mutex
+
+	quitCh   chan struct{}
+	workCh   chan *Work
+	returnCh chan<- *Result
+
+	chain  consensus.ChainReader
+	engine consensus.Engine
+
+	currentWork *Work
+	work        map[common.Hash]*Work
+	workedAt    map[common.Hash]time.Time
+
+	hashrateMu sync.RWMutex
+	hashrate   map[common.Hash]hashrateReport
+
+	running int32 // running indicates whether the agent is currently accepting work
+}
+
+// NewRemoteAgent creates an agent that hands pending work to external miners
+// over RPC instead of sealing it with the local CPU.
+func NewRemoteAgent(chain consensus.ChainReader, engine consensus.Engine) *RemoteAgent {
+	return &RemoteAgent{
+		chain:    chain,
+		engine:   engine,
+		work:     make(map[common.Hash]*Work),
+		workedAt: make(map[common.Hash]time.Time),
+		hashrate: make(map[common.Hash]hashrateReport),
+	}
+}
+
+func (a *RemoteAgent) Work() chan<- *Work            { return a.workCh }
+func (a *RemoteAgent) SetReturnCh(ch chan<- *Result) { a.returnCh = ch }
+
+func (a *RemoteAgent) Start() {
+	if !atomic.CompareAndSwapInt32(&a.running, 0, 1) {
+		return // agent already started
+	}
+	a.quitCh = make(chan struct{})
+	a.workCh = make(chan *Work, 1)
+	go a.loop(a.workCh, a.quitCh)
+}
+
+func (a *RemoteAgent) Stop() {
+	if !atomic.CompareAndSwapInt32(&a.running, 1, 0) {
+		return // agent already stopped
+	}
+	close(a.quitCh)
+}
+
+func (a *RemoteAgent) GetHashRate() (tot int64) {
+	a.hashrateMu.RLock()
+	defer a.hashrateMu.RUnlock()
+	for _, report := range a.hashrate {
+		tot += int64(report.rate)
+	}
+	return tot
+}
+
+// SubmitHashrate records an external miner's self-reported hashrate, keyed
+// by an opaque id it picks for itself. This backs irc_submitHashrate, whose
+// RPC shape puts the rate before the id and encodes it as a hex quantity.
+func (a *RemoteAgent) SubmitHashrate(rate hexutil.Uint64, id common.Hash) {
+	a.hashrateMu.Lock()
+	defer a.hashrateMu.Unlock()
+	a.hashrate[id] = hashrateReport{ping: time.Now(), rate: uint64(rate)}
+}
+
+// GetWork returns the [headerHash, seedHash, target] job description for
+// the current work, caching it under headerHash so a later SubmitWork can
+// find it again. This backs irc_getWork.
+func (a *RemoteAgent) GetWork() ([3]string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	var res [3]string
+	if a.currentWork == nil {
+		return res, errors.New("no work available yet, don't panic")
+	}
+	block := a.currentWork.Block
+	hash := block.HashNoNonce()
+
+	res[0] = hash.Hex()
+	res[1] = common.BytesToHash(ethash.SeedHash(block.NumberU64())).Hex()
+	res[2] = common.BytesToHash(sealTarget(block.Difficulty()).Bytes()).Hex()
+
+	if _, cached := a.work[hash]; !cached {
+		a.work[hash] = a.currentWork
+		a.workedAt[hash] = time.Now()
+	}
+	return res, nil
+}
+
+// sealTarget converts a block's difficulty into the 256-bit target an
+// external miner compares its hash against, the inverse of how difficulty
+// itself is derived from a 2^256 search space.
+func sealTarget(difficulty *big.Int) *big.Int {
+	target := new(big.Int).Lsh(big.NewInt(1), 256)
+	return target.Div(target, difficulty)
+}
+
+// SubmitWork tries to inject a PoW solution for headerHash into the remote
+// agent, returning whether it was accepted. The first valid submission for
+// a header wins; concurrent submissions for the same header after that are
+// rejected because the cached job has already been removed. This backs
+// irc_submitWork.
+func (a *RemoteAgent) SubmitWork(nonce types.BlockNonce, headerHash, mixDigest common.Hash) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	work, ok := a.work[headerHash]
+	if !ok {
+		log.Info("Work submitted but none pending", "hash", headerHash)
+		return false
+	}
+	header := work.Block.Header()
+	header.Nonce = nonce
+	header.MixDigest = mixDigest
+
+	if err := a.engine.VerifySeal(a.chain, header); err != nil {
+		log.Warn("Invalid proof-of-work submitted", "hash", headerHash, "err", err)
+		return false
+	}
+	sealed := work.Block.WithSeal(header)
+
+	// Non-blocking: if nothing is listening (or SetReturnCh was never
+	// called), drop the result rather than wedge GetWork/SubmitWork/the
+	// expiry loop for the whole agent while holding a.mu.
+	select {
+	case a.returnCh <- &Result{work, sealed}:
+	default:
+		log.Warn("Sealed block not read by miner", "hash", headerHash)
+	}
+	delete(a.work, headerHash)
+	delete(a.workedAt, headerHash)
+
+	return true
+}
+
+// loop accepts newly assigned work and periodically expires jobs and
+// hashrate reports that have gone stale.
+func (a *RemoteAgent) loop(workCh chan *Work, quitCh chan struct{}) {
+	ticker := time.NewTicker(jobExpiry / staleJobBlocks)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-quitCh:
+			return
+		case work := <-workCh:
+			a.mu.Lock()
+			a.currentWork = work
+			a.mu.Unlock()
+		case <-ticker.C:
+			a.mu.Lock()
+			for hash, workedAt := range a.workedAt {
+				if time.Since(workedAt) > jobExpiry {
+					delete(a.work, hash)
+					delete(a.workedAt, hash)
+				}
+			}
+			a.mu.Unlock()
+
+			a.hashrateMu.Lock()
+			for id, report := range a.hashrate {
+				if time.Since(report.ping) > 10*time.Second {
+					delete(a.hashrate, id)
+				}
+			}
+			a.hashrateMu.Unlock()
+		}
+	}
+}